@@ -0,0 +1,73 @@
+package bitflyer
+
+import (
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		httpStatus int
+		body       string
+		wantMsg    string
+	}{
+		{
+			name:       "well-formed error envelope",
+			httpStatus: 400,
+			body:       `{"status":-1,"error_message":"Invalid request parameters."}`,
+			wantMsg:    "bitflyer: request failed: http 400: status -1: Invalid request parameters.",
+		},
+		{
+			name:       "non-JSON body falls back to raw body",
+			httpStatus: 500,
+			body:       "internal server error",
+			wantMsg:    "bitflyer: request failed: http 500: status 0: internal server error",
+		},
+		{
+			name:       "JSON body without error_message falls back to raw body",
+			httpStatus: 400,
+			body:       `{"status":-1}`,
+			wantMsg:    `bitflyer: request failed: http 400: status -1: {"status":-1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseAPIError(tt.httpStatus, []byte(tt.body))
+			if got := err.Error(); got != tt.wantMsg {
+				t.Errorf("parseAPIError(%d, %q).Error() = %q, want %q", tt.httpStatus, tt.body, got, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestLimiterForEndpoint(t *testing.T) {
+	api := New("key", "secret")
+
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+	}{
+		{name: "send child order", endpoint: "/v1/me/sendchildorder", want: "order"},
+		{name: "send parent order", endpoint: "/v1/me/sendparentorder", want: "order"},
+		{name: "other private endpoint", endpoint: "/v1/me/getbalance", want: "private"},
+		{name: "public endpoint", endpoint: "/v1/ticker", want: "public"},
+		{name: "endpoint without the /v1/me/ prefix is not private", endpoint: "/v1/me", want: "public"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiters := map[string]*rate.Limiter{
+				"order":   api.orderLimiter,
+				"private": api.privateLimiter,
+				"public":  api.publicLimiter,
+			}
+			want := limiters[tt.want]
+			got := api.limiterForEndpoint(tt.endpoint)
+			if got != want {
+				t.Errorf("limiterForEndpoint(%q) = %p, want %p (%s)", tt.endpoint, got, want, tt.want)
+			}
+		})
+	}
+}
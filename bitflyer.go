@@ -2,16 +2,23 @@ package bitflyer
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -19,24 +26,125 @@ const (
 	URL            = "https://api.bitflyer.jp"
 	minuteToExpire = 525600
 	timeInForce    = "GTC"
+
+	defaultMaxRetries = 2
+	retryBaseDelay    = 500 * time.Millisecond
 )
 
 // APIClient struct represents bitFlyer Lightning API client.
 type APIClient struct {
-	key    string
-	secret string
-	client *http.Client
+	key       string
+	secret    string
+	client    *http.Client
+	baseURL   string
+	userAgent string
+	debug     bool
+	logger    *log.Logger
+
+	publicLimiter  *rate.Limiter
+	privateLimiter *rate.Limiter
+	orderLimiter   *rate.Limiter
+	maxRetries     int
+}
+
+// APIError represents bitFlyer's JSON error envelope,
+// {"status":-208,"error_message":"...","data":null}, returned on non-2xx
+// HTTP responses.
+type APIError struct {
+	Status     int    `json:"status"`
+	Message    string `json:"error_message"`
+	HTTPStatus int    `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bitflyer: request failed: http %d: status %d: %s", e.HTTPStatus, e.Status, e.Message)
+}
+
+// Option configures an APIClient created by New.
+type Option func(*APIClient)
+
+// WithHTTPClient sets the *http.Client used for every request, e.g. to point
+// it at a proxy or a mock transport in tests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(api *APIClient) {
+		api.client = httpClient
+	}
+}
+
+// WithBaseURL overrides the API base URL (URL by default), e.g. to target a
+// mock server in integration tests.
+func WithBaseURL(baseURL string) Option {
+	return func(api *APIClient) {
+		api.baseURL = baseURL
+	}
+}
+
+// WithTimeout sets a timeout on the underlying *http.Client. The default
+// *http.Client has no timeout, so a stuck request hangs forever unless this
+// or WithHTTPClient is used.
+func WithTimeout(timeout time.Duration) Option {
+	return func(api *APIClient) {
+		api.client.Timeout = timeout
+	}
+}
+
+// WithDebug turns on logging of signed request and response bodies via the
+// client's logger (os.Stderr unless overridden with WithLogger).
+func WithDebug(debug bool) Option {
+	return func(api *APIClient) {
+		api.debug = debug
+	}
+}
+
+// WithLogger sets the writer debug logging is written to.
+func WithLogger(w io.Writer) Option {
+	return func(api *APIClient) {
+		api.logger = log.New(w, "bitflyer: ", log.LstdFlags)
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(api *APIClient) {
+		api.userAgent = userAgent
+	}
+}
+
+// WithRateLimiter overrides the token-bucket limiters used to throttle
+// requests within bitFlyer's published limits: public endpoints, private
+// endpoints, and the stricter order-placement endpoints each have their own
+// limiter. Pass nil for a group to keep its default.
+func WithRateLimiter(public, private, order *rate.Limiter) Option {
+	return func(api *APIClient) {
+		if public != nil {
+			api.publicLimiter = public
+		}
+		if private != nil {
+			api.privateLimiter = private
+		}
+		if order != nil {
+			api.orderLimiter = order
+		}
+	}
+}
+
+// WithMaxRetries sets how many times a request is retried after an HTTP 429
+// or 5xx response, with exponential backoff between attempts.
+func WithMaxRetries(maxRetries int) Option {
+	return func(api *APIClient) {
+		api.maxRetries = maxRetries
+	}
 }
 
 // AskBid represents bitFlyer Lightning order book ask or bid record.
 type AskBid struct {
-	Price float64 `json:"price"`
-	Size  float64 `json:"size"`
+	Price Decimal `json:"price"`
+	Size  Decimal `json:"size"`
 }
 
 // OrderBook represents bitFlyer Lightning order book.
 type OrderBook struct {
-	MidPrice float64  `json:"mid_price"`
+	MidPrice Decimal  `json:"mid_price"`
 	Bids     []AskBid `json:"bids"`
 	Asks     []AskBid `json:"asks"`
 }
@@ -47,8 +155,8 @@ type AssetBalance []Balance
 // Balance represents bitFlyer Lightning asset balance record.
 type Balance struct {
 	CurrencyCode string  `json:"currency_code"`
-	Amount       float64 `json:"amount"`
-	Available    float64 `json:"available"`
+	Amount       Decimal `json:"amount"`
+	Available    Decimal `json:"available"`
 }
 
 // Ticker represents bitFlyer Lightning ticker.
@@ -56,15 +164,15 @@ type Ticker struct {
 	ProductCode     string  `json:"product_code"`
 	Timestamp       string  `json:"timestamp"`
 	TickID          int     `json:"tick_id"`
-	BestBid         float64 `json:"best_bid"`
-	BestAsk         float64 `json:"best_ask"`
-	BestBidSize     float64 `json:"best_bid_size"`
-	BestAskSize     float64 `json:"best_ask_size"`
-	TotalBidDepth   float64 `json:"total_bid_depth"`
-	TotalAskDepth   float64 `json:"total_ask_depth"`
-	LTP             float64 `json:"ltp"`
-	Volume          float64 `json:"volume"`
-	VolumeByProduct float64 `json:"volume_by_product"`
+	BestBid         Decimal `json:"best_bid"`
+	BestAsk         Decimal `json:"best_ask"`
+	BestBidSize     Decimal `json:"best_bid_size"`
+	BestAskSize     Decimal `json:"best_ask_size"`
+	TotalBidDepth   Decimal `json:"total_bid_depth"`
+	TotalAskDepth   Decimal `json:"total_ask_depth"`
+	LTP             Decimal `json:"ltp"`
+	Volume          Decimal `json:"volume"`
+	VolumeByProduct Decimal `json:"volume_by_product"`
 }
 
 // Order represents a new child order.
@@ -73,26 +181,47 @@ type Order struct {
 	ProductCode            string  `json:"product_code"`
 	ChildOrderType         string  `json:"child_order_type"`
 	Side                   string  `json:"side"`
-	Price                  float64 `json:"price"`
-	Size                   float64 `json:"size"`
+	Price                  Decimal `json:"price"`
+	Size                   Decimal `json:"size"`
 	MinuteToExpires        int     `json:"minute_to_expire"`
 	TimeInForce            string  `json:"time_in_force"`
 	Status                 int     `json:"status"`
 	ErrorMessage           string  `json:"error_message"`
 }
 
-// New creates a new bitFlyer Lightning API client.
-func New(key, secret string) (client *APIClient) {
-	client = new(APIClient)
-	client.key = key
-	client.secret = secret
-	client.client = new(http.Client)
+// New creates a new bitFlyer Lightning API client. Without options it uses a
+// plain *http.Client with no timeout talking to the production URL, the
+// default rate limiters below, and up to defaultMaxRetries retries.
+func New(key, secret string, opts ...Option) (client *APIClient) {
+	client = &APIClient{
+		key:     key,
+		secret:  secret,
+		client:  new(http.Client),
+		baseURL: URL,
+		logger:  log.New(os.Stderr, "bitflyer: ", log.LstdFlags),
+		// Defaults approximate bitFlyer's published limits: ~500 requests per
+		// 5 minutes per IP, with order placement limited to ~300 per 5
+		// minutes per user.
+		publicLimiter:  rate.NewLimiter(rate.Every(5*time.Minute/500), 10),
+		privateLimiter: rate.NewLimiter(rate.Every(5*time.Minute/500), 10),
+		orderLimiter:   rate.NewLimiter(rate.Every(5*time.Minute/300), 5),
+		maxRetries:     defaultMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
 	return client
 }
 
 // GetOrderBook returns bitFlyer Lightning order book.
 func (api APIClient) GetOrderBook() (orderBook OrderBook, err error) {
-	err = api.doGetRequest("/v1/getboard", []byte(""), &orderBook)
+	return api.GetOrderBookContext(context.Background())
+}
+
+// GetOrderBookContext is like GetOrderBook but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetOrderBookContext(ctx context.Context) (orderBook OrderBook, err error) {
+	err = api.doGetRequest(ctx, "/v1/getboard", []byte(""), &orderBook)
 	if err != nil {
 		return orderBook, err
 	}
@@ -101,7 +230,13 @@ func (api APIClient) GetOrderBook() (orderBook OrderBook, err error) {
 
 // GetBalance returns bitFlyer Lightning account asset balance.
 func (api APIClient) GetBalance() (assetBalance AssetBalance, err error) {
-	err = api.doGetRequest("/v1/me/getbalance", []byte(""), &assetBalance)
+	return api.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext is like GetBalance but propagates ctx to the underlying
+// HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetBalanceContext(ctx context.Context) (assetBalance AssetBalance, err error) {
+	err = api.doGetRequest(ctx, "/v1/me/getbalance", []byte(""), &assetBalance)
 	if err != nil {
 		return assetBalance, err
 	}
@@ -110,7 +245,13 @@ func (api APIClient) GetBalance() (assetBalance AssetBalance, err error) {
 
 // GetTicker returns bitFlyer Lightning ticker.
 func (api APIClient) GetTicker() (ticker Ticker, err error) {
-	err = api.doGetRequest("/v1/getticker", []byte(""), &ticker)
+	return api.GetTickerContext(context.Background())
+}
+
+// GetTickerContext is like GetTicker but propagates ctx to the underlying
+// HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetTickerContext(ctx context.Context) (ticker Ticker, err error) {
+	err = api.doGetRequest(ctx, "/v1/getticker", []byte(""), &ticker)
 	if err != nil {
 		return ticker, err
 	}
@@ -119,6 +260,12 @@ func (api APIClient) GetTicker() (ticker Ticker, err error) {
 
 // NewOrder sends a new order.
 func (api APIClient) NewOrder(order Order) (newOrder Order, err error) {
+	return api.NewOrderContext(context.Background(), order)
+}
+
+// NewOrderContext is like NewOrder but propagates ctx to the underlying HTTP
+// request, allowing the caller to cancel it in flight.
+func (api APIClient) NewOrderContext(ctx context.Context, order Order) (newOrder Order, err error) {
 	newOrder = order
 	if newOrder.MinuteToExpires <= 0 {
 		newOrder.MinuteToExpires = minuteToExpire
@@ -130,7 +277,7 @@ func (api APIClient) NewOrder(order Order) (newOrder Order, err error) {
 	if err != nil {
 		return newOrder, err
 	}
-	err = api.doPostRequest("/v1/me/sendchildorder", data, &newOrder)
+	err = api.doPostRequest(ctx, "/v1/me/sendchildorder", data, &newOrder)
 	if err != nil {
 		return newOrder, err
 	}
@@ -140,9 +287,23 @@ func (api APIClient) NewOrder(order Order) (newOrder Order, err error) {
 	return newOrder, nil
 }
 
-func (api *APIClient) doGetRequest(endpoint string, body []byte, data interface{}) (err error) {
+// NewOrderFromFloats is a migration helper for callers still passing price
+// and size as float64: it converts them to Decimal and forwards to NewOrder.
+func (api APIClient) NewOrderFromFloats(order Order, price, size float64) (Order, error) {
+	return api.NewOrderFromFloatsContext(context.Background(), order, price, size)
+}
+
+// NewOrderFromFloatsContext is like NewOrderFromFloats but propagates ctx to
+// the underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) NewOrderFromFloatsContext(ctx context.Context, order Order, price, size float64) (Order, error) {
+	order.Price = NewDecimalFromFloat(price)
+	order.Size = NewDecimalFromFloat(size)
+	return api.NewOrderContext(ctx, order)
+}
+
+func (api *APIClient) doGetRequest(ctx context.Context, endpoint string, body []byte, data interface{}) (err error) {
 	headers := headers(api.key, api.secret, "GET", endpoint, string(body))
-	resp, err := api.doRequest("GET", endpoint, body, headers)
+	resp, err := api.doRequest(ctx, "GET", endpoint, body, headers)
 	if err != nil {
 		return err
 	}
@@ -153,12 +314,18 @@ func (api *APIClient) doGetRequest(endpoint string, body []byte, data interface{
 	return nil
 }
 
-func (api *APIClient) doPostRequest(endpoint string, body []byte, data interface{}) (err error) {
+func (api *APIClient) doPostRequest(ctx context.Context, endpoint string, body []byte, data interface{}) (err error) {
 	headers := headers(api.key, api.secret, "POST", endpoint, string(body))
-	resp, err := api.doRequest("POST", endpoint, body, headers)
+	resp, err := api.doRequest(ctx, "POST", endpoint, body, headers)
 	if err != nil {
 		return err
 	}
+	// Some endpoints (e.g. cancelchildorder, cancelallchildorders,
+	// cancelparentorder) respond 200 with an empty body on success; there's
+	// nothing to unmarshal into data in that case.
+	if len(resp) == 0 {
+		return nil
+	}
 	err = json.Unmarshal(resp, data)
 	if err != nil {
 		return err
@@ -166,22 +333,77 @@ func (api *APIClient) doPostRequest(endpoint string, body []byte, data interface
 	return nil
 }
 
-func (api *APIClient) doRequest(method, endpoint string, data []byte, headers map[string]string) ([]byte, error) {
-	req, err := http.NewRequest(method, URL+endpoint, bytes.NewBuffer(data))
-	if err != nil {
-		return nil, requestError(err.Error())
+func (api *APIClient) doRequest(ctx context.Context, method, endpoint string, data []byte, headers map[string]string) ([]byte, error) {
+	limiter := api.limiterForEndpoint(endpoint)
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, requestError(err.Error())
+		}
+		req, err := http.NewRequestWithContext(ctx, method, api.baseURL+endpoint, bytes.NewReader(data))
+		if err != nil {
+			return nil, requestError(err.Error())
+		}
+		setHeaders(req, headers)
+		if api.userAgent != "" {
+			req.Header.Set("User-Agent", api.userAgent)
+		}
+		if api.debug {
+			api.logger.Printf("request: %s %s body=%s", method, endpoint, string(data))
+		}
+		resp, err := api.client.Do(req)
+		if err != nil {
+			return nil, requestError(err.Error())
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, requestError(err.Error())
+		}
+		if api.debug {
+			api.logger.Printf("response: %d body=%s", resp.StatusCode, string(body))
+		}
+
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		if retryable && attempt < api.maxRetries {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, requestError(ctx.Err().Error())
+			}
+			delay *= 2
+			continue
+		}
+		if resp.StatusCode >= http.StatusMultipleChoices {
+			return nil, parseAPIError(resp.StatusCode, body)
+		}
+		return body, nil
 	}
-	setHeaders(req, headers)
-	resp, err := api.client.Do(req)
-	if err != nil {
-		return nil, requestError(err.Error())
+}
+
+// limiterForEndpoint picks the rate limiter matching bitFlyer's per-endpoint
+// limit groups: order placement, other private endpoints, and public
+// endpoints.
+func (api *APIClient) limiterForEndpoint(endpoint string) *rate.Limiter {
+	switch {
+	case strings.HasPrefix(endpoint, "/v1/me/sendchildorder"), strings.HasPrefix(endpoint, "/v1/me/sendparentorder"):
+		return api.orderLimiter
+	case strings.HasPrefix(endpoint, "/v1/me/"):
+		return api.privateLimiter
+	default:
+		return api.publicLimiter
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, requestError(err.Error())
+}
+
+// parseAPIError decodes bitFlyer's {"status":...,"error_message":...} error
+// envelope. If the body doesn't match that shape, the raw body is carried in
+// Message instead.
+func parseAPIError(httpStatus int, body []byte) error {
+	apiErr := &APIError{HTTPStatus: httpStatus}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = string(body)
 	}
-	return body, nil
+	return apiErr
 }
 
 func headers(key, secret, method, uri, body string) map[string]string {
@@ -205,7 +427,7 @@ func computeHmac256(message string, secret string) string {
 }
 
 func requestError(err interface{}) error {
-	return fmt.Errorf("Could not execute request! (%s)", err)
+	return fmt.Errorf("could not execute request: %s", err)
 }
 
 func setHeaders(req *http.Request, headers map[string]string) {
@@ -9,14 +9,13 @@ import (
 func TestGetBalanceFail(t *testing.T) {
 
 	api := New("wrong_key", "wrong_password")
-	ret, body, err := api.GetBalance()
+	ret, err := api.GetBalance()
 	log.Printf("err:%v", err)
 	if err == nil {
 		panic("should be error")
 	}
 
 	log.Printf("ret:%v", ret)
-	log.Printf("body:%s", string(body))
 
 	return
 }
@@ -26,14 +25,13 @@ func TestGetBalance(t *testing.T) {
 	secret := os.Getenv("BITFLYER_SECRET")
 
 	api := New(key, secret)
-	ret, body, err := api.GetBalance()
+	ret, err := api.GetBalance()
 	if err != nil {
 		panic(err)
 	}
 
 	log.Printf("err:%v", err)
 	log.Printf("ret:%v", ret)
-	log.Printf("body:%s", string(body))
 
 	return
 }
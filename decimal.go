@@ -0,0 +1,139 @@
+package bitflyer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decimalScale is the fixed-point scale factor of Decimal, which keeps 8
+// decimal places, matching bitFlyer's finest BTC size increment
+// (1 satoshi = 0.00000001 BTC).
+const decimalScale = 100000000 // 1e8
+
+// Decimal is a fixed-point decimal value used for prices and sizes. Unlike
+// float64, it round-trips exactly through JSON and doesn't accumulate error
+// when summed, which matters for JPY prices and satoshi-level BTC sizes. The
+// zero value represents 0.
+type Decimal int64
+
+// NewDecimalFromFloat converts a float64 to a Decimal, rounding to 8 decimal
+// places. It exists for migrating callers still working with float64; prefer
+// letting json.Unmarshal populate a Decimal directly, which avoids float64
+// entirely.
+func NewDecimalFromFloat(f float64) Decimal {
+	if f < 0 {
+		return Decimal(f*decimalScale - 0.5)
+	}
+	return Decimal(f*decimalScale + 0.5)
+}
+
+// Float64 returns d as a float64, for callers that need to do further math
+// with the standard library.
+func (d Decimal) Float64() float64 {
+	return float64(d) / decimalScale
+}
+
+// String returns d formatted as a plain decimal number, e.g. "0.00000001" or
+// "941500".
+func (d Decimal) String() string {
+	neg := ""
+	n := int64(d)
+	if n < 0 {
+		neg = "-"
+		n = -n
+	}
+	s := fmt.Sprintf("%s%d.%08d", neg, n/decimalScale, n%decimalScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Add returns d + other.
+func (d Decimal) Add(other Decimal) Decimal {
+	return d + other
+}
+
+// Sub returns d - other.
+func (d Decimal) Sub(other Decimal) Decimal {
+	return d - other
+}
+
+// Cmp compares d and other, returning -1, 0 or 1 as d is less than, equal to,
+// or greater than other.
+func (d Decimal) Cmp(other Decimal) int {
+	switch {
+	case d < other:
+		return -1
+	case d > other:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// MarshalJSON implements json.Marshaler, encoding d as a plain JSON number
+// with as many decimal digits as it needs (up to 8).
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts both bare JSON
+// numbers (bitFlyer's current format) and quoted-string numbers (in case a
+// future endpoint quotes them to dodge float precision issues of its own),
+// parsing the decimal digits directly rather than round-tripping through
+// float64. Numbers in exponent notation (e.g. 1e-8) are rejected with an
+// error.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*d = 0
+		return nil
+	}
+	n, err := parseDecimalString(s)
+	if err != nil {
+		return fmt.Errorf("bitflyer: invalid decimal %q: %w", s, err)
+	}
+	*d = Decimal(n)
+	return nil
+}
+
+func parseDecimalString(s string) (int64, error) {
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if len(fracPart) > 8 {
+		// Round to 8 decimal places rather than truncate.
+		round := fracPart[8] >= '5'
+		fracPart = fracPart[:8]
+		if round {
+			n, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			n++
+			if neg {
+				n = -n
+			}
+			return n, nil
+		}
+	}
+	for len(fracPart) < 8 {
+		fracPart += "0"
+	}
+
+	n, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
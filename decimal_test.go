@@ -0,0 +1,121 @@
+package bitflyer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecimalUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Decimal
+		wantErr bool
+	}{
+		{name: "integer", input: `100`, want: 100 * decimalScale},
+		{name: "decimal", input: `0.5`, want: 50000000},
+		{name: "eight decimal places", input: `0.00000001`, want: 1},
+		{name: "fewer than eight decimal places", input: `1.1`, want: 110000000},
+		{name: "rounds half up past eight decimal places", input: `0.000000015`, want: 2},
+		{name: "rounds down past eight decimal places", input: `0.000000014`, want: 1},
+		{name: "negative", input: `-941500.5`, want: -94150050000000},
+		{name: "quoted integer", input: `"100"`, want: 100 * decimalScale},
+		{name: "quoted decimal", input: `"0.00000001"`, want: 1},
+		{name: "explicit plus sign", input: `"+5"`, want: 5 * decimalScale},
+		{name: "null", input: `null`, want: 0},
+		{name: "empty string", input: `""`, want: 0},
+		{name: "not a number", input: `"abc"`, wantErr: true},
+		{name: "overflows int64", input: `99999999999999999999`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Decimal
+			err := json.Unmarshal([]byte(tt.input), &d)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%q): expected an error, got Decimal %v", tt.input, d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%q): unexpected error: %v", tt.input, err)
+			}
+			if d != tt.want {
+				t.Errorf("Unmarshal(%q) = %v, want %v", tt.input, d, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		d    Decimal
+		want string
+	}{
+		{name: "zero", d: 0, want: "0"},
+		{name: "whole number", d: 100 * decimalScale, want: "100"},
+		{name: "trims trailing zeros", d: 150000000, want: "1.5"},
+		{name: "keeps all eight decimal places", d: 1, want: "0.00000001"},
+		{name: "negative", d: -50000000, want: "-0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.d)
+			if err != nil {
+				t.Fatalf("Marshal(%v): unexpected error: %v", tt.d, err)
+			}
+			if got := string(data); got != tt.want {
+				t.Errorf("Marshal(%v) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecimalRoundTrip(t *testing.T) {
+	for _, s := range []string{"0", "0.00000001", "941500", "1.23456789", "-3.5"} {
+		var d Decimal
+		if err := json.Unmarshal([]byte(s), &d); err != nil {
+			t.Fatalf("Unmarshal(%q): unexpected error: %v", s, err)
+		}
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Fatalf("Marshal after Unmarshal(%q): unexpected error: %v", s, err)
+		}
+		var got Decimal
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("re-Unmarshal(%q): unexpected error: %v", string(data), err)
+		}
+		if got != d {
+			t.Errorf("round trip of %q: got %v, want %v", s, got, d)
+		}
+	}
+}
+
+func TestDecimalFloat64(t *testing.T) {
+	d := NewDecimalFromFloat(0.1)
+	if got := d.Float64(); got != 0.1 {
+		t.Errorf("Float64() = %v, want 0.1", got)
+	}
+}
+
+func TestDecimalArithmeticAndCmp(t *testing.T) {
+	a := NewDecimalFromFloat(1.5)
+	b := NewDecimalFromFloat(0.5)
+
+	if got := a.Add(b); got != NewDecimalFromFloat(2) {
+		t.Errorf("Add = %v, want 2", got)
+	}
+	if got := a.Sub(b); got != NewDecimalFromFloat(1) {
+		t.Errorf("Sub = %v, want 1", got)
+	}
+	if a.Cmp(b) != 1 {
+		t.Errorf("Cmp(a, b) = %d, want 1", a.Cmp(b))
+	}
+	if b.Cmp(a) != -1 {
+		t.Errorf("Cmp(b, a) = %d, want -1", b.Cmp(a))
+	}
+	if a.Cmp(a) != 0 {
+		t.Errorf("Cmp(a, a) = %d, want 0", a.Cmp(a))
+	}
+}
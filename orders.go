@@ -0,0 +1,420 @@
+package bitflyer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// ChildOrderQuery represents filters for GetChildOrders.
+type ChildOrderQuery struct {
+	ProductCode     string
+	ChildOrderState string
+	ParentOrderID   string
+	Count           int
+	Before          int
+	After           int
+}
+
+func (q ChildOrderQuery) queryString() string {
+	values := url.Values{}
+	if q.ProductCode != "" {
+		values.Set("product_code", q.ProductCode)
+	}
+	if q.ChildOrderState != "" {
+		values.Set("child_order_state", q.ChildOrderState)
+	}
+	if q.ParentOrderID != "" {
+		values.Set("parent_order_id", q.ParentOrderID)
+	}
+	if q.Count > 0 {
+		values.Set("count", strconv.Itoa(q.Count))
+	}
+	if q.Before > 0 {
+		values.Set("before", strconv.Itoa(q.Before))
+	}
+	if q.After > 0 {
+		values.Set("after", strconv.Itoa(q.After))
+	}
+	return values.Encode()
+}
+
+// ChildOrderStatus represents the status of a child order returned from GetChildOrders.
+type ChildOrderStatus struct {
+	ID                     int     `json:"id"`
+	ChildOrderID           string  `json:"child_order_id"`
+	ProductCode            string  `json:"product_code"`
+	Side                   string  `json:"side"`
+	ChildOrderType         string  `json:"child_order_type"`
+	Price                  Decimal `json:"price"`
+	AveragePrice           Decimal `json:"average_price"`
+	Size                   Decimal `json:"size"`
+	ChildOrderState        string  `json:"child_order_state"`
+	ExpireDate             string  `json:"expire_date"`
+	ChildOrderDate         string  `json:"child_order_date"`
+	ChildOrderAcceptanceID string  `json:"child_order_acceptance_id"`
+	OutstandingSize        Decimal `json:"outstanding_size"`
+	CancelSize             Decimal `json:"cancel_size"`
+	ExecutedSize           Decimal `json:"executed_size"`
+	TotalCommission        Decimal `json:"total_commission"`
+}
+
+// ExecutionsQuery represents filters for GetExecutions.
+type ExecutionsQuery struct {
+	ProductCode            string
+	ChildOrderID           string
+	ChildOrderAcceptanceID string
+	Count                  int
+	Before                 int
+	After                  int
+}
+
+func (q ExecutionsQuery) queryString() string {
+	values := url.Values{}
+	if q.ProductCode != "" {
+		values.Set("product_code", q.ProductCode)
+	}
+	if q.ChildOrderID != "" {
+		values.Set("child_order_id", q.ChildOrderID)
+	}
+	if q.ChildOrderAcceptanceID != "" {
+		values.Set("child_order_acceptance_id", q.ChildOrderAcceptanceID)
+	}
+	if q.Count > 0 {
+		values.Set("count", strconv.Itoa(q.Count))
+	}
+	if q.Before > 0 {
+		values.Set("before", strconv.Itoa(q.Before))
+	}
+	if q.After > 0 {
+		values.Set("after", strconv.Itoa(q.After))
+	}
+	return values.Encode()
+}
+
+// Execution represents a single execution of the authenticated user's child order.
+type Execution struct {
+	ID                     int     `json:"id"`
+	ChildOrderID           string  `json:"child_order_id"`
+	Side                   string  `json:"side"`
+	Price                  Decimal `json:"price"`
+	Size                   Decimal `json:"size"`
+	Commission             Decimal `json:"commission"`
+	ExecDate               string  `json:"exec_date"`
+	ChildOrderAcceptanceID string  `json:"child_order_acceptance_id"`
+}
+
+// Position represents an open position in a margin product (e.g. FX_BTC_JPY).
+type Position struct {
+	ProductCode         string  `json:"product_code"`
+	Side                string  `json:"side"`
+	Price               Decimal `json:"price"`
+	Size                Decimal `json:"size"`
+	Commission          Decimal `json:"commission"`
+	SwapPointAccumulate Decimal `json:"swap_point_accumulate"`
+	RequireCollateral   Decimal `json:"require_collateral"`
+	OpenDate            string  `json:"open_date"`
+	Leverage            Decimal `json:"leverage"`
+	Pnl                 Decimal `json:"pnl"`
+	Sfd                 Decimal `json:"sfd"`
+}
+
+// Collateral represents the authenticated user's margin collateral status.
+type Collateral struct {
+	Collateral        Decimal `json:"collateral"`
+	OpenPositionPnl   Decimal `json:"open_position_pnl"`
+	RequireCollateral Decimal `json:"require_collateral"`
+	KeepRate          Decimal `json:"keep_rate"`
+}
+
+// ParentOrderParameter represents a single leg of a parent order. OrderMethod
+// on the enclosing ParentOrder determines how many parameters are required:
+// one for "SIMPLE", two for "IFD" or "OCO", three for "IFDOCO".
+type ParentOrderParameter struct {
+	ProductCode   string  `json:"product_code"`
+	ConditionType string  `json:"condition_type"`
+	Side          string  `json:"side"`
+	Price         Decimal `json:"price,omitempty"`
+	Size          Decimal `json:"size"`
+	TriggerPrice  Decimal `json:"trigger_price,omitempty"`
+	Offset        Decimal `json:"offset,omitempty"`
+}
+
+// ParentOrder represents a new parent (special) order such as IFD, OCO or IFDOCO.
+type ParentOrder struct {
+	ParentOrderID           string                 `json:"parent_order_id"`
+	ParentOrderAcceptanceID string                 `json:"parent_order_acceptance_id"`
+	OrderMethod             string                 `json:"order_method"`
+	MinuteToExpires         int                    `json:"minute_to_expire"`
+	TimeInForce             string                 `json:"time_in_force"`
+	Parameters              []ParentOrderParameter `json:"parameters"`
+	Status                  int                    `json:"status"`
+	ErrorMessage            string                 `json:"error_message"`
+}
+
+// ParentOrderQuery represents filters for GetParentOrders.
+type ParentOrderQuery struct {
+	ProductCode      string
+	ParentOrderState string
+	Count            int
+	Before           int
+	After            int
+}
+
+func (q ParentOrderQuery) queryString() string {
+	values := url.Values{}
+	if q.ProductCode != "" {
+		values.Set("product_code", q.ProductCode)
+	}
+	if q.ParentOrderState != "" {
+		values.Set("parent_order_state", q.ParentOrderState)
+	}
+	if q.Count > 0 {
+		values.Set("count", strconv.Itoa(q.Count))
+	}
+	if q.Before > 0 {
+		values.Set("before", strconv.Itoa(q.Before))
+	}
+	if q.After > 0 {
+		values.Set("after", strconv.Itoa(q.After))
+	}
+	return values.Encode()
+}
+
+// ParentOrderStatus represents the status of a parent order returned from
+// GetParentOrders or GetParentOrder.
+type ParentOrderStatus struct {
+	ID                      int     `json:"id"`
+	ParentOrderID           string  `json:"parent_order_id"`
+	ProductCode             string  `json:"product_code"`
+	Side                    string  `json:"side"`
+	ParentOrderType         string  `json:"parent_order_type"`
+	Price                   Decimal `json:"price"`
+	AveragePrice            Decimal `json:"average_price"`
+	Size                    Decimal `json:"size"`
+	ParentOrderState        string  `json:"parent_order_state"`
+	ExpireDate              string  `json:"expire_date"`
+	ParentOrderDate         string  `json:"parent_order_date"`
+	ParentOrderAcceptanceID string  `json:"parent_order_acceptance_id"`
+	OutstandingSize         Decimal `json:"outstanding_size"`
+	CancelSize              Decimal `json:"cancel_size"`
+	ExecutedSize            Decimal `json:"executed_size"`
+	TotalCommission         Decimal `json:"total_commission"`
+	// Parameters holds the order's legs; only GetParentOrder fills it in.
+	Parameters []ParentOrderParameter `json:"parameters,omitempty"`
+}
+
+// CancelChildOrder cancels a single child order identified by acceptanceID.
+func (api APIClient) CancelChildOrder(productCode, acceptanceID string) error {
+	return api.CancelChildOrderContext(context.Background(), productCode, acceptanceID)
+}
+
+// CancelChildOrderContext is like CancelChildOrder but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) CancelChildOrderContext(ctx context.Context, productCode, acceptanceID string) error {
+	req := struct {
+		ProductCode            string `json:"product_code"`
+		ChildOrderAcceptanceID string `json:"child_order_acceptance_id"`
+	}{productCode, acceptanceID}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var resp struct{}
+	return api.doPostRequest(ctx, "/v1/me/cancelchildorder", data, &resp)
+}
+
+// CancelAllChildOrders cancels every open child order for productCode.
+func (api APIClient) CancelAllChildOrders(productCode string) error {
+	return api.CancelAllChildOrdersContext(context.Background(), productCode)
+}
+
+// CancelAllChildOrdersContext is like CancelAllChildOrders but propagates ctx
+// to the underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) CancelAllChildOrdersContext(ctx context.Context, productCode string) error {
+	req := struct {
+		ProductCode string `json:"product_code"`
+	}{productCode}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var resp struct{}
+	return api.doPostRequest(ctx, "/v1/me/cancelallchildorders", data, &resp)
+}
+
+// GetChildOrders returns the authenticated user's child orders matching req.
+func (api APIClient) GetChildOrders(req ChildOrderQuery) (orders []ChildOrderStatus, err error) {
+	return api.GetChildOrdersContext(context.Background(), req)
+}
+
+// GetChildOrdersContext is like GetChildOrders but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetChildOrdersContext(ctx context.Context, req ChildOrderQuery) (orders []ChildOrderStatus, err error) {
+	endpoint := "/v1/me/getchildorders?" + req.queryString()
+	err = api.doGetRequest(ctx, endpoint, []byte(""), &orders)
+	if err != nil {
+		return orders, err
+	}
+	return orders, nil
+}
+
+// GetExecutions returns the authenticated user's executions matching req.
+func (api APIClient) GetExecutions(req ExecutionsQuery) (executions []Execution, err error) {
+	return api.GetExecutionsContext(context.Background(), req)
+}
+
+// GetExecutionsContext is like GetExecutions but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetExecutionsContext(ctx context.Context, req ExecutionsQuery) (executions []Execution, err error) {
+	endpoint := "/v1/me/getexecutions?" + req.queryString()
+	err = api.doGetRequest(ctx, endpoint, []byte(""), &executions)
+	if err != nil {
+		return executions, err
+	}
+	return executions, nil
+}
+
+// GetPositions returns the authenticated user's open positions in productCode,
+// which must be a margin product such as FX_BTC_JPY.
+func (api APIClient) GetPositions(productCode string) (positions []Position, err error) {
+	return api.GetPositionsContext(context.Background(), productCode)
+}
+
+// GetPositionsContext is like GetPositions but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetPositionsContext(ctx context.Context, productCode string) (positions []Position, err error) {
+	endpoint := "/v1/me/getpositions?" + url.Values{"product_code": {productCode}}.Encode()
+	err = api.doGetRequest(ctx, endpoint, []byte(""), &positions)
+	if err != nil {
+		return positions, err
+	}
+	return positions, nil
+}
+
+// GetCollateral returns the authenticated user's margin collateral status.
+func (api APIClient) GetCollateral() (collateral Collateral, err error) {
+	return api.GetCollateralContext(context.Background())
+}
+
+// GetCollateralContext is like GetCollateral but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetCollateralContext(ctx context.Context) (collateral Collateral, err error) {
+	err = api.doGetRequest(ctx, "/v1/me/getcollateral", []byte(""), &collateral)
+	if err != nil {
+		return collateral, err
+	}
+	return collateral, nil
+}
+
+// SendParentOrder sends a new parent (special) order, such as an IFD, OCO or
+// IFDOCO composed of the order's Parameters.
+func (api APIClient) SendParentOrder(order ParentOrder) (newOrder ParentOrder, err error) {
+	return api.SendParentOrderContext(context.Background(), order)
+}
+
+// SendParentOrderContext is like SendParentOrder but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) SendParentOrderContext(ctx context.Context, order ParentOrder) (newOrder ParentOrder, err error) {
+	newOrder = order
+	if newOrder.MinuteToExpires <= 0 {
+		newOrder.MinuteToExpires = minuteToExpire
+	}
+	if newOrder.TimeInForce == "" {
+		newOrder.TimeInForce = timeInForce
+	}
+	data, err := json.Marshal(newOrder)
+	if err != nil {
+		return newOrder, err
+	}
+	err = api.doPostRequest(ctx, "/v1/me/sendparentorder", data, &newOrder)
+	if err != nil {
+		return newOrder, err
+	}
+	if newOrder.ErrorMessage != "" {
+		return newOrder, errors.New(newOrder.ErrorMessage)
+	}
+	return newOrder, nil
+}
+
+// GetParentOrders returns the authenticated user's parent orders matching req.
+func (api APIClient) GetParentOrders(req ParentOrderQuery) (orders []ParentOrderStatus, err error) {
+	return api.GetParentOrdersContext(context.Background(), req)
+}
+
+// GetParentOrdersContext is like GetParentOrders but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetParentOrdersContext(ctx context.Context, req ParentOrderQuery) (orders []ParentOrderStatus, err error) {
+	endpoint := "/v1/me/getparentorders?" + req.queryString()
+	err = api.doGetRequest(ctx, endpoint, []byte(""), &orders)
+	if err != nil {
+		return orders, err
+	}
+	return orders, nil
+}
+
+// GetParentOrder returns a single parent order's status and its Parameters.
+// Identify the order by parentOrderID or parentOrderAcceptanceID, passing the
+// other as an empty string.
+func (api APIClient) GetParentOrder(parentOrderID, parentOrderAcceptanceID string) (order ParentOrderStatus, err error) {
+	return api.GetParentOrderContext(context.Background(), parentOrderID, parentOrderAcceptanceID)
+}
+
+// GetParentOrderContext is like GetParentOrder but propagates ctx to the
+// underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) GetParentOrderContext(ctx context.Context, parentOrderID, parentOrderAcceptanceID string) (order ParentOrderStatus, err error) {
+	values := url.Values{}
+	if parentOrderID != "" {
+		values.Set("parent_order_id", parentOrderID)
+	}
+	if parentOrderAcceptanceID != "" {
+		values.Set("parent_order_acceptance_id", parentOrderAcceptanceID)
+	}
+	endpoint := "/v1/me/getparentorder?" + values.Encode()
+	err = api.doGetRequest(ctx, endpoint, []byte(""), &order)
+	if err != nil {
+		return order, err
+	}
+	return order, nil
+}
+
+// CancelParentOrder cancels a parent order identified by parentOrderID or
+// parentOrderAcceptanceID, passing the other as an empty string.
+func (api APIClient) CancelParentOrder(productCode, parentOrderID, parentOrderAcceptanceID string) error {
+	return api.CancelParentOrderContext(context.Background(), productCode, parentOrderID, parentOrderAcceptanceID)
+}
+
+// CancelParentOrderContext is like CancelParentOrder but propagates ctx to
+// the underlying HTTP request, allowing the caller to cancel it in flight.
+func (api APIClient) CancelParentOrderContext(ctx context.Context, productCode, parentOrderID, parentOrderAcceptanceID string) error {
+	req := struct {
+		ProductCode             string `json:"product_code"`
+		ParentOrderID           string `json:"parent_order_id,omitempty"`
+		ParentOrderAcceptanceID string `json:"parent_order_acceptance_id,omitempty"`
+	}{productCode, parentOrderID, parentOrderAcceptanceID}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var resp struct{}
+	return api.doPostRequest(ctx, "/v1/me/cancelparentorder", data, &resp)
+}
+
+// ReplaceOrder cancels an existing child order and sends newOrder in its
+// place. bitFlyer has no atomic replace endpoint, so this is best-effort: the
+// cancel completes before the new order is sent, but the two calls are not
+// atomic against the matching engine.
+func (api APIClient) ReplaceOrder(productCode, acceptanceID string, newOrder Order) (Order, error) {
+	return api.ReplaceOrderContext(context.Background(), productCode, acceptanceID, newOrder)
+}
+
+// ReplaceOrderContext is like ReplaceOrder but propagates ctx to the
+// underlying HTTP requests, allowing the caller to cancel them in flight.
+func (api APIClient) ReplaceOrderContext(ctx context.Context, productCode, acceptanceID string, newOrder Order) (Order, error) {
+	if err := api.CancelChildOrderContext(ctx, productCode, acceptanceID); err != nil {
+		return Order{}, err
+	}
+	return api.NewOrderContext(ctx, newOrder)
+}
@@ -0,0 +1,367 @@
+// Package realtime implements a client for the bitFlyer Lightning Realtime
+// API, a JSON-RPC 2.0 over WebSocket stream that pushes ticker, order book
+// and execution updates, as well as private order events.
+package realtime
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WSURL is the bitFlyer Lightning Realtime API endpoint.
+const WSURL = "wss://ws.lightstream.bitflyer.com/json-rpc"
+
+const (
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// subscription tracks a single channel subscription so it can be replayed
+// against a fresh connection after a reconnect.
+type subscription struct {
+	channel  string
+	private  bool
+	dispatch func(json.RawMessage)
+}
+
+// RealtimeClient represents a bitFlyer Lightning Realtime API client. Use New
+// to create one; the zero value is not usable.
+type RealtimeClient struct {
+	key    string
+	secret string
+
+	mu            sync.Mutex
+	conn          *websocket.Conn
+	nextID        int
+	subscriptions map[string]*subscription
+
+	// writeMu serializes WriteJSON calls across goroutines: gorilla/websocket
+	// allows only one concurrent writer, and send() can be called both from
+	// user goroutines (Subscribe*) and from the readLoop goroutine (via
+	// resubscribeAll after a reconnect).
+	writeMu sync.Mutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a new bitFlyer Lightning Realtime API client and connects to
+// the Realtime API. key and secret are only required to subscribe to private
+// channels (child_order_events, parent_order_events); pass empty strings for
+// public-only usage.
+func New(key, secret string) (*RealtimeClient, error) {
+	rc := &RealtimeClient{
+		key:           key,
+		secret:        secret,
+		subscriptions: make(map[string]*subscription),
+		closed:        make(chan struct{}),
+	}
+	if err := rc.connect(); err != nil {
+		return nil, err
+	}
+	go rc.readLoop()
+	return rc, nil
+}
+
+// Close shuts down the connection and stops the background read and
+// reconnect goroutines. It is safe to call Close more than once.
+func (rc *RealtimeClient) Close() error {
+	rc.closeOnce.Do(func() {
+		close(rc.closed)
+		rc.mu.Lock()
+		if rc.conn != nil {
+			rc.conn.Close()
+		}
+		rc.mu.Unlock()
+	})
+	return nil
+}
+
+// SubscribeTicker subscribes to the lightning_ticker channel for productCode
+// and returns a channel of ticker updates.
+func (rc *RealtimeClient) SubscribeTicker(productCode string) (<-chan Ticker, error) {
+	ch := make(chan Ticker, 64)
+	channel := "lightning_ticker_" + productCode
+	err := rc.subscribe(channel, false, func(raw json.RawMessage) {
+		var t Ticker
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return
+		}
+		select {
+		case ch <- t:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// SubscribeBoard subscribes to the lightning_board channel for productCode,
+// which carries incremental order book updates: losing one means every
+// update after it reconstructs the wrong book. Unlike the other Subscribe*
+// methods, its channel is unbuffered and dispatch blocks until the caller
+// receives, so no delta is dropped because the caller is slow; a caller that
+// falls behind stalls this client's read loop, and with it every other
+// subscription on this connection, rather than desync, so drain the returned
+// channel promptly. Deltas sent while the connection is down are not
+// replayed after a reconnect.
+func (rc *RealtimeClient) SubscribeBoard(productCode string) (<-chan OrderBook, error) {
+	ch := make(chan OrderBook)
+	err := rc.subscribe("lightning_board_"+productCode, false, func(raw json.RawMessage) {
+		var ob OrderBook
+		if err := json.Unmarshal(raw, &ob); err != nil {
+			return
+		}
+		select {
+		case ch <- ob:
+		case <-rc.closed:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// SubscribeBoardSnapshot subscribes to the lightning_board_snapshot channel
+// for productCode, which carries the full order book on every message, so a
+// dropped message is superseded by the next one rather than compounding.
+func (rc *RealtimeClient) SubscribeBoardSnapshot(productCode string) (<-chan OrderBook, error) {
+	ch := make(chan OrderBook, 64)
+	err := rc.subscribe("lightning_board_snapshot_"+productCode, false, func(raw json.RawMessage) {
+		var ob OrderBook
+		if err := json.Unmarshal(raw, &ob); err != nil {
+			return
+		}
+		select {
+		case ch <- ob:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// SubscribeExecutions subscribes to the lightning_executions channel for
+// productCode and returns a channel of executions.
+func (rc *RealtimeClient) SubscribeExecutions(productCode string) (<-chan Execution, error) {
+	ch := make(chan Execution, 64)
+	channel := "lightning_executions_" + productCode
+	err := rc.subscribe(channel, false, func(raw json.RawMessage) {
+		var execs []Execution
+		if err := json.Unmarshal(raw, &execs); err != nil {
+			return
+		}
+		for _, e := range execs {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// SubscribeChildOrderEvents subscribes to the private child_order_events
+// channel. It authenticates the connection first, using key and secret.
+func (rc *RealtimeClient) SubscribeChildOrderEvents() (<-chan ChildOrderEvent, error) {
+	if err := rc.authenticate(); err != nil {
+		return nil, err
+	}
+	ch := make(chan ChildOrderEvent, 64)
+	err := rc.subscribe("child_order_events", true, func(raw json.RawMessage) {
+		var events []ChildOrderEvent
+		if err := json.Unmarshal(raw, &events); err != nil {
+			return
+		}
+		for _, e := range events {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// SubscribeParentOrderEvents subscribes to the private parent_order_events
+// channel. It authenticates the connection first, using key and secret.
+func (rc *RealtimeClient) SubscribeParentOrderEvents() (<-chan ParentOrderEvent, error) {
+	if err := rc.authenticate(); err != nil {
+		return nil, err
+	}
+	ch := make(chan ParentOrderEvent, 64)
+	err := rc.subscribe("parent_order_events", true, func(raw json.RawMessage) {
+		var events []ParentOrderEvent
+		if err := json.Unmarshal(raw, &events); err != nil {
+			return
+		}
+		for _, e := range events {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (rc *RealtimeClient) subscribe(channel string, private bool, dispatch func(json.RawMessage)) error {
+	rc.mu.Lock()
+	rc.subscriptions[channel] = &subscription{channel: channel, private: private, dispatch: dispatch}
+	rc.mu.Unlock()
+	return rc.send("subscribe", subscribeParams{Channel: channel})
+}
+
+// authenticate signs and sends the auth JSON-RPC call required before
+// subscribing to private channels. The signing scheme mirrors computeHmac256
+// in the REST client: an HMAC-SHA256 of timestamp+nonce, keyed by secret.
+func (rc *RealtimeClient) authenticate() error {
+	if rc.key == "" || rc.secret == "" {
+		return errors.New("realtime: key and secret are required to subscribe to private channels")
+	}
+	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
+	nonce, err := randomNonce()
+	if err != nil {
+		return err
+	}
+	signature := computeHmac256(fmt.Sprintf("%d%s", timestamp, nonce), rc.secret)
+	return rc.send("auth", authParams{
+		APIKey:    rc.key,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: signature,
+	})
+}
+
+func (rc *RealtimeClient) send(method string, params interface{}) error {
+	rc.mu.Lock()
+	rc.nextID++
+	req := rpcRequest{JSONRPC: "2.0", Method: method, Params: params, ID: rc.nextID}
+	conn := rc.conn
+	rc.mu.Unlock()
+	if conn == nil {
+		return errors.New("realtime: not connected")
+	}
+	rc.writeMu.Lock()
+	defer rc.writeMu.Unlock()
+	return conn.WriteJSON(req)
+}
+
+func (rc *RealtimeClient) connect() error {
+	conn, _, err := websocket.DefaultDialer.Dial(WSURL, nil)
+	if err != nil {
+		return fmt.Errorf("realtime: dial: %w", err)
+	}
+	rc.mu.Lock()
+	rc.conn = conn
+	rc.mu.Unlock()
+	return nil
+}
+
+func (rc *RealtimeClient) readLoop() {
+	for {
+		rc.mu.Lock()
+		conn := rc.conn
+		rc.mu.Unlock()
+
+		var notification rpcNotification
+		err := conn.ReadJSON(&notification)
+		if err != nil {
+			select {
+			case <-rc.closed:
+				return
+			default:
+			}
+			rc.reconnect()
+			continue
+		}
+		if notification.Method != "channelMessage" {
+			continue
+		}
+		rc.mu.Lock()
+		sub := rc.subscriptions[notification.Params.Channel]
+		rc.mu.Unlock()
+		if sub != nil {
+			sub.dispatch(notification.Params.Message)
+		}
+	}
+}
+
+// reconnect redials the Realtime API with exponential backoff, then replays
+// every active subscription (and re-authenticates if any of them are
+// private) before returning.
+func (rc *RealtimeClient) reconnect() {
+	delay := minReconnectDelay
+	for {
+		select {
+		case <-rc.closed:
+			return
+		case <-time.After(delay):
+		}
+		if err := rc.connect(); err == nil {
+			break
+		}
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+	rc.resubscribeAll()
+}
+
+func (rc *RealtimeClient) resubscribeAll() {
+	rc.mu.Lock()
+	subs := make([]*subscription, 0, len(rc.subscriptions))
+	for _, sub := range rc.subscriptions {
+		subs = append(subs, sub)
+	}
+	rc.mu.Unlock()
+
+	authenticated := false
+	for _, sub := range subs {
+		if sub.private && !authenticated {
+			if err := rc.authenticate(); err != nil {
+				continue
+			}
+			authenticated = true
+		}
+		rc.send("subscribe", subscribeParams{Channel: sub.channel})
+	}
+}
+
+func randomNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func computeHmac256(message, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(message))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,115 @@
+package realtime
+
+import "encoding/json"
+
+// AskBid represents a single price level in a bitFlyer Lightning order book update.
+type AskBid struct {
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+// OrderBook represents a bitFlyer Lightning order book update received over the
+// lightning_board_<product_code> and lightning_board_snapshot_<product_code> channels.
+type OrderBook struct {
+	MidPrice float64  `json:"mid_price"`
+	Bids     []AskBid `json:"bids"`
+	Asks     []AskBid `json:"asks"`
+}
+
+// Ticker represents a bitFlyer Lightning ticker update received over the
+// lightning_ticker_<product_code> channel.
+type Ticker struct {
+	ProductCode     string  `json:"product_code"`
+	Timestamp       string  `json:"timestamp"`
+	TickID          int     `json:"tick_id"`
+	BestBid         float64 `json:"best_bid"`
+	BestAsk         float64 `json:"best_ask"`
+	BestBidSize     float64 `json:"best_bid_size"`
+	BestAskSize     float64 `json:"best_ask_size"`
+	TotalBidDepth   float64 `json:"total_bid_depth"`
+	TotalAskDepth   float64 `json:"total_ask_depth"`
+	LTP             float64 `json:"ltp"`
+	Volume          float64 `json:"volume"`
+	VolumeByProduct float64 `json:"volume_by_product"`
+}
+
+// Execution represents a single execution received over the
+// lightning_executions_<product_code> channel.
+type Execution struct {
+	ID                         int64   `json:"id"`
+	Side                       string  `json:"side"`
+	Price                      float64 `json:"price"`
+	Size                       float64 `json:"size"`
+	ExecDate                   string  `json:"exec_date"`
+	BuyChildOrderAcceptanceID  string  `json:"buy_child_order_acceptance_id"`
+	SellChildOrderAcceptanceID string  `json:"sell_child_order_acceptance_id"`
+}
+
+// ChildOrderEvent represents an order lifecycle event received over the
+// private child_order_events channel.
+type ChildOrderEvent struct {
+	ProductCode            string  `json:"product_code"`
+	ChildOrderID           string  `json:"child_order_id"`
+	ChildOrderAcceptanceID string  `json:"child_order_acceptance_id"`
+	EventType              string  `json:"event_type"`
+	ChildOrderType         string  `json:"child_order_type"`
+	Side                   string  `json:"side"`
+	Price                  float64 `json:"price"`
+	Size                   float64 `json:"size"`
+	ExpireDate             string  `json:"expire_date"`
+	Reason                 string  `json:"reason"`
+	ExecDate               string  `json:"exec_date"`
+	ExecutionID            int64   `json:"execution_id"`
+	CommissionCollection   float64 `json:"commission"`
+}
+
+// ParentOrderEvent represents an order lifecycle event received over the
+// private parent_order_events channel.
+type ParentOrderEvent struct {
+	ProductCode             string  `json:"product_code"`
+	ParentOrderID           string  `json:"parent_order_id"`
+	ParentOrderAcceptanceID string  `json:"parent_order_acceptance_id"`
+	EventType               string  `json:"event_type"`
+	ParentOrderType         string  `json:"parent_order_type"`
+	Side                    string  `json:"side"`
+	Price                   float64 `json:"price"`
+	Size                    float64 `json:"size"`
+	ExpireDate              string  `json:"expire_date"`
+	Reason                  string  `json:"reason"`
+}
+
+// rpcRequest is a JSON-RPC 2.0 request frame sent to the Realtime API.
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      int         `json:"id,omitempty"`
+}
+
+// rpcNotification is a JSON-RPC 2.0 notification frame pushed by the server,
+// most notably the channelMessage frames that carry subscribed channel data.
+type rpcNotification struct {
+	JSONRPC string         `json:"jsonrpc"`
+	Method  string         `json:"method"`
+	Params  channelMessage `json:"params"`
+}
+
+// channelMessage is the params payload of a channelMessage notification.
+type channelMessage struct {
+	Channel string          `json:"channel"`
+	Message json.RawMessage `json:"message"`
+}
+
+// subscribeParams is the params payload of a subscribe call.
+type subscribeParams struct {
+	Channel string `json:"channel"`
+}
+
+// authParams is the params payload of the auth call used to authenticate
+// before subscribing to private channels.
+type authParams struct {
+	APIKey    string `json:"api_key"`
+	Timestamp int64  `json:"timestamp"`
+	Nonce     string `json:"nonce"`
+	Signature string `json:"signature"`
+}